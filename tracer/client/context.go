@@ -0,0 +1,97 @@
+package client
+
+import (
+	"context"
+	"tracy/log"
+	"tracy/tracer/types"
+)
+
+/*AddTracersContext is the context-aware version of AddTracers. It's
+ * dispatched through the active Transport (HTTP+JSON by default, or gRPC when
+ * "tracer-transport" is set to "grpc"). A failed add is not retried. */
+func AddTracersContext(ctx context.Context, request types.Request) error {
+	err := activeTransport().AddTracers(ctx, request)
+	if err != nil {
+		log.Warning.Printf(err.Error())
+	}
+	return err
+}
+
+/*GetTracersContext is the context-aware version of GetTracers. The HTTP
+ * transport retries it with backoff on network errors and 5xx responses; the
+ * gRPC transport does not retry. */
+func GetTracersContext(ctx context.Context) ([]types.Tracer, error) {
+	tracers, err := activeTransport().GetTracers(ctx)
+	if err != nil {
+		log.Warning.Printf(err.Error())
+	}
+	return tracers, err
+}
+
+/*AddTracerEventsContext is the context-aware version of AddTracerEvents.
+ * If the event sink is enabled (see sink.go), every event is queued for a later
+ * batched flush instead of being posted here and now. Otherwise the events are
+ * sent once, without retries. */
+func AddTracerEventsContext(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	if sink := activeEventSink(); sink != nil {
+		var errs []error
+		for tracerID, tracerEvent := range tracerEvents {
+			if err := sink.Push(ctx, tracerID, tracerEvent); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errs
+	}
+
+	errs := activeTransport().AddTracerEvents(ctx, tracerEvents)
+	for _, err := range errs {
+		log.Warning.Printf(err.Error())
+	}
+	return errs
+}
+
+/*AddTracerEventContext is the context-aware version of AddTracerEvent.
+ * If the event sink is enabled (see sink.go), the event is queued for a later
+ * batched flush instead of being posted here and now. Otherwise the event is
+ * sent once, without retries. */
+func AddTracerEventContext(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	if sink := activeEventSink(); sink != nil {
+		return sink.Push(ctx, tracerID, tracerEvent)
+	}
+
+	err := activeTransport().AddTracerEvent(ctx, tracerEvent, tracerID)
+	if err != nil {
+		log.Warning.Println(err)
+	}
+	return err
+}
+
+/*AddLabelContext is the context-aware version of AddLabel. A failed add is
+ * not retried. */
+func AddLabelContext(ctx context.Context, label types.Label) error {
+	err := activeTransport().AddLabel(ctx, label)
+	if err != nil {
+		log.Warning.Println(err)
+	}
+	return err
+}
+
+/*GetLabelsContext is the context-aware version of GetLabels, retried the same
+ * way as GetTracersContext (HTTP transport only). */
+func GetLabelsContext(ctx context.Context) ([]types.Label, error) {
+	labels, err := activeTransport().GetLabels(ctx)
+	if err != nil {
+		log.Warning.Println(err)
+	}
+	return labels, err
+}
+
+/*GetLabelContext is the context-aware version of GetLabel, retried the same
+ * way as GetTracersContext (HTTP transport only). */
+func GetLabelContext(ctx context.Context, ID int) (types.Label, error) {
+	label, err := activeTransport().GetLabel(ctx, ID)
+	if err != nil {
+		log.Warning.Printf(err.Error())
+	}
+	return label, err
+}
@@ -0,0 +1,63 @@
+package client
+
+import "fmt"
+
+/*NetworkError indicates that the request never made it to the tracer-server, or the
+ * response never made it back (connection refused, DNS failure, timeout, context
+ * cancellation, etc). The wrapped error is the one returned by the underlying
+ * net/http call. The gRPC transport reports every failed call as a
+ * NetworkError wrapping the gRPC error, including a status error that the
+ * server itself sent back. */
+type NetworkError struct {
+	Op  string
+	Err error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("tracer-server request failed (%s): %s", e.Op, e.Err.Error())
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+/*ServerError indicates that the tracer-server was reached but responded with a
+ * non-2xx status code. Body holds whatever the server sent back so callers can
+ * surface it in logs or error messages without re-reading the response. */
+type ServerError struct {
+	Op         string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("tracer-server returned %d for %s: %s", e.StatusCode, e.Op, e.Body)
+}
+
+/*DecodeError indicates that the tracer-server responded successfully, but the
+ * response body couldn't be unmarshalled into the expected type. */
+type DecodeError struct {
+	Op  string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("failed to decode %s response: %s", e.Op, e.Err.Error())
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+/*isRetryable reports whether an error returned from a request is worth retrying:
+ * network failures and 5xx responses are, 4xx responses and decode failures are not. */
+func isRetryable(err error) bool {
+	switch e := err.(type) {
+	case *NetworkError:
+		return true
+	case *ServerError:
+		return e.StatusCode >= 500
+	default:
+		return false
+	}
+}
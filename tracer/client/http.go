@@ -0,0 +1,139 @@
+package client
+
+import (
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"tracy/configure"
+)
+
+/* Defaults used when the relevant keys aren't present in the configure store, so
+ * existing configs don't break when this client is upgraded. */
+const (
+	defaultClientTimeout  = 10 * time.Second
+	defaultMaxIdleConns   = 100
+	defaultMaxRetries     = 3
+	defaultRetryBaseDelay = 100 * time.Millisecond
+	defaultRetryMaxDelay  = 2 * time.Second
+)
+
+var (
+	httpClientOnce sync.Once
+	httpClient     *http.Client
+
+	streamingClientOnce sync.Once
+	streamingClient     *http.Client
+)
+
+/*sharedHTTPClient returns the package-wide *http.Client used by every request function.
+ * It's built once, lazily, from the tracer-server config block so that every caller
+ * shares the same connection pool and keep-alives instead of paying a fresh TCP/TLS
+ * handshake per request. */
+func sharedHTTPClient() *http.Client {
+	httpClientOnce.Do(func() {
+		httpClient = &http.Client{
+			Timeout: configuredTimeout(),
+			Transport: &http.Transport{
+				MaxIdleConns:        configuredMaxIdleConns(),
+				MaxIdleConnsPerHost: configuredMaxIdleConns(),
+				IdleConnTimeout:     90 * time.Second,
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+			},
+		}
+	})
+	return httpClient
+}
+
+/*streamingHTTPClient returns the package-wide *http.Client used for long-lived
+ * SSE subscriptions (see transport_http.go's openSSE). Unlike sharedHTTPClient
+ * it sets no overall Timeout, since that would cut a subscription off after
+ * configuredTimeout regardless of how healthy the connection is; the caller's
+ * ctx is what actually bounds a subscription's lifetime. */
+func streamingHTTPClient() *http.Client {
+	streamingClientOnce.Do(func() {
+		streamingClient = &http.Client{
+			Transport: &http.Transport{
+				MaxIdleConns:        configuredMaxIdleConns(),
+				MaxIdleConnsPerHost: configuredMaxIdleConns(),
+				IdleConnTimeout:     90 * time.Second,
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+			},
+		}
+	})
+	return streamingClient
+}
+
+/*configuredTimeout reads the per-call timeout from the tracer-server config block,
+ * falling back to defaultClientTimeout if it isn't set. */
+func configuredTimeout() time.Duration {
+	if raw, err := configure.ReadConfig("tracer-server-timeout-ms"); err == nil {
+		if ms, ok := raw.(float64); ok && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+	return defaultClientTimeout
+}
+
+func configuredMaxIdleConns() int {
+	if raw, err := configure.ReadConfig("tracer-server-max-idle-conns"); err == nil {
+		if n, ok := raw.(float64); ok && n > 0 {
+			return int(n)
+		}
+	}
+	return defaultMaxIdleConns
+}
+
+/*retryPolicy describes an exponential backoff-with-jitter retry schedule for a
+ * single logical request. */
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+/*configuredRetryPolicy reads the maximum number of attempts from the
+ * "tracer-server-max-retries" config key, falling back to defaultMaxRetries.
+ * The base and maximum backoff delays are always the defaults. A maxAttempts
+ * of 1 disables retries entirely. */
+func configuredRetryPolicy() retryPolicy {
+	policy := retryPolicy{
+		maxAttempts: defaultMaxRetries,
+		baseDelay:   defaultRetryBaseDelay,
+		maxDelay:    defaultRetryMaxDelay,
+	}
+
+	if raw, err := configure.ReadConfig("tracer-server-max-retries"); err == nil {
+		if n, ok := raw.(float64); ok && n >= 1 {
+			policy.maxAttempts = int(n)
+		}
+	}
+
+	return policy
+}
+
+/*backoff returns how long to sleep before the given attempt (0-indexed), applying
+ * full jitter so that a fleet of proxies retrying at once doesn't thunder the
+ * tracer-server all at the same instant. attempt is clamped before shifting so
+ * an indefinitely-reconnecting caller (see subscribe.go, which only resets its
+ * attempt counter on a successful receive) can't shift baseDelay past maxDelay
+ * and overflow into a negative duration. */
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	const maxShift = 62 // baseDelay << 62 already dwarfs any sane maxDelay
+	if attempt > maxShift {
+		attempt = maxShift
+	}
+
+	delay := p.baseDelay * time.Duration(1<<uint(attempt))
+	if delay <= 0 || delay > p.maxDelay {
+		delay = p.maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
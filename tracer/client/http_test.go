@@ -0,0 +1,42 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoffIsBounded(t *testing.T) {
+	policy := retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}
+
+	for attempt := 0; attempt < 10; attempt++ {
+		delay := policy.backoff(attempt)
+		if delay < 0 || delay > policy.maxDelay {
+			t.Fatalf("backoff(%d) = %s, want within [0, %s]", attempt, delay, policy.maxDelay)
+		}
+	}
+}
+
+func TestRetryPolicyBackoffZeroAttemptStaysAtBaseOrBelow(t *testing.T) {
+	policy := retryPolicy{baseDelay: 50 * time.Millisecond, maxDelay: time.Second}
+
+	for i := 0; i < 20; i++ {
+		if delay := policy.backoff(0); delay > policy.baseDelay {
+			t.Fatalf("backoff(0) = %s, want at most baseDelay %s", delay, policy.baseDelay)
+		}
+	}
+}
+
+/*TestRetryPolicyBackoffDoesNotOverflowOnUnboundedAttempt is a regression test
+ * for an indefinitely-reconnecting caller (subscribe.go's reconnect loop only
+ * resets its attempt counter on a successful receive) shifting baseDelay past
+ * an int64 and coming out negative, which used to make rand.Int63n panic. */
+func TestRetryPolicyBackoffDoesNotOverflowOnUnboundedAttempt(t *testing.T) {
+	policy := retryPolicy{baseDelay: 100 * time.Millisecond, maxDelay: 2 * time.Second}
+
+	for _, attempt := range []int{60, 62, 64, 100, 1000} {
+		delay := policy.backoff(attempt)
+		if delay < 0 || delay > policy.maxDelay {
+			t.Fatalf("backoff(%d) = %s, want within [0, %s]", attempt, delay, policy.maxDelay)
+		}
+	}
+}
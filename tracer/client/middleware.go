@@ -0,0 +1,557 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tracy/configure"
+	"tracy/log"
+	"tracy/tracer/types"
+)
+
+/* Middleware config keys. Every one of them defaults to its inert zero value, so
+ * none of this changes behavior unless a config explicitly opts in. */
+const (
+	configCircuitBreakerEnabled       = "tracer-client-circuit-breaker-enabled"
+	configCircuitBreakerThreshold     = "tracer-client-circuit-breaker-threshold"
+	configCircuitBreakerHalfOpenMs    = "tracer-client-circuit-breaker-half-open-ms"
+	configRateLimitQPS                = "tracer-client-rate-limit-qps"
+	configTracingEnabled              = "tracer-client-tracing-enabled"
+	defaultCircuitBreakerThreshold    = 5
+	defaultCircuitBreakerHalfOpenTime = 30 * time.Second
+)
+
+/*wrapWithMiddleware layers the configured middleware around a base Transport, in
+ * the order circuit breaker -> rate limiter -> tracing, so that a tripped breaker
+ * short-circuits before the rate limiter does any bookkeeping, and a span covers
+ * only the work that actually reached the network. Each wrapper is applied
+ * outside the previous one, so the call order at request time is the reverse of
+ * the order they're wrapped in here: the circuit breaker first, closest to the
+ * caller, then rate limiting, then tracing closest to the base Transport. */
+func wrapWithMiddleware(base Transport) Transport {
+	t := base
+
+	if configBool(configTracingEnabled) {
+		t = newTracingTransport(t)
+	}
+
+	if qps := configFloat(configRateLimitQPS); qps > 0 {
+		t = newRateLimiterTransport(t, qps)
+	}
+
+	if configBool(configCircuitBreakerEnabled) {
+		t = newCircuitBreakerTransport(t)
+	}
+
+	return t
+}
+
+func configBool(key string) bool {
+	raw, err := configure.ReadConfig(key)
+	if err != nil {
+		return false
+	}
+	enabled, ok := raw.(bool)
+	return ok && enabled
+}
+
+func configFloat(key string) float64 {
+	raw, err := configure.ReadConfig(key)
+	if err != nil {
+		return 0
+	}
+	val, ok := raw.(float64)
+	if !ok {
+		return 0
+	}
+	return val
+}
+
+/* --- circuit breaker ------------------------------------------------------- */
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+/*endpointBreaker is a per-endpoint circuit breaker: it opens after a run of
+ * consecutive failed calls, rejects calls immediately while open, and allows a single probe request through once the
+ * half-open timeout elapses. probing tracks whether that one probe is still in
+ * flight, so concurrent callers don't all get waved through at once the moment
+ * the breaker flips to half-open. Every error the wrapped Transport returns
+ * counts as a failure, not only 5xx responses and dial/network errors: a 4xx
+ * response, a decode failure or a cancelled context counts as well. */
+type endpointBreaker struct {
+	mu               sync.Mutex
+	state            breakerState
+	consecutiveFails int
+	openedAt         time.Time
+	threshold        int
+	halfOpenAfter    time.Duration
+	probing          bool
+}
+
+func newEndpointBreaker() *endpointBreaker {
+	threshold := int(configFloat(configCircuitBreakerThreshold))
+	if threshold <= 0 {
+		threshold = defaultCircuitBreakerThreshold
+	}
+
+	halfOpenAfter := defaultCircuitBreakerHalfOpenTime
+	if ms := configFloat(configCircuitBreakerHalfOpenMs); ms > 0 {
+		halfOpenAfter = time.Duration(ms) * time.Millisecond
+	}
+
+	return &endpointBreaker{threshold: threshold, halfOpenAfter: halfOpenAfter}
+}
+
+/*allow reports whether a call should be let through, transitioning an open
+ * breaker to half-open once its timeout has elapsed. Only one caller is let
+ * through as the half-open probe; every other caller is rejected until that
+ * probe's outcome is recorded via recordSuccess or recordFailure, so a burst
+ * of concurrent requests can't all pile onto a server that's still unhealthy. */
+func (b *endpointBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) >= b.halfOpenAfter {
+			b.state = breakerHalfOpen
+			b.probing = true
+			return true
+		}
+		return false
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (b *endpointBreaker) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.consecutiveFails = 0
+	b.probing = false
+	b.state = breakerClosed
+}
+
+func (b *endpointBreaker) recordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.consecutiveFails++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.consecutiveFails >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+/*circuitBreakerErr is returned in place of a network call when the breaker for
+ * that endpoint is open, so a dead tracer-server doesn't stall every browser
+ * action being proxied behind it. */
+type circuitBreakerErr struct {
+	op string
+}
+
+func (e *circuitBreakerErr) Error() string {
+	return fmt.Sprintf("circuit breaker open for %s, tracer-server looks unhealthy", e.op)
+}
+
+type circuitBreakerTransport struct {
+	next     Transport
+	mu       sync.Mutex
+	breakers map[string]*endpointBreaker
+}
+
+func newCircuitBreakerTransport(next Transport) Transport {
+	return &circuitBreakerTransport{next: next, breakers: make(map[string]*endpointBreaker)}
+}
+
+func (t *circuitBreakerTransport) breakerFor(op string) *endpointBreaker {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.breakers[op]
+	if !ok {
+		b = newEndpointBreaker()
+		t.breakers[op] = b
+	}
+	return b
+}
+
+func (t *circuitBreakerTransport) guard(op string, fn func() error) error {
+	b := t.breakerFor(op)
+	if !b.allow() {
+		return &circuitBreakerErr{op: op}
+	}
+
+	err := fn()
+	if err != nil {
+		b.recordFailure()
+	} else {
+		b.recordSuccess()
+	}
+	return err
+}
+
+func (t *circuitBreakerTransport) AddTracers(ctx context.Context, request types.Request) error {
+	return t.guard("AddTracers", func() error { return t.next.AddTracers(ctx, request) })
+}
+
+func (t *circuitBreakerTransport) GetTracers(ctx context.Context) ([]types.Tracer, error) {
+	var ret []types.Tracer
+	err := t.guard("GetTracers", func() error {
+		var innerErr error
+		ret, innerErr = t.next.GetTracers(ctx)
+		return innerErr
+	})
+	return ret, err
+}
+
+func (t *circuitBreakerTransport) AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	return t.guard("AddTracerEvent", func() error { return t.next.AddTracerEvent(ctx, tracerEvent, tracerID) })
+}
+
+func (t *circuitBreakerTransport) AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	b := t.breakerFor("AddTracerEvents")
+	if !b.allow() {
+		return []error{&circuitBreakerErr{op: "AddTracerEvents"}}
+	}
+
+	errs := t.next.AddTracerEvents(ctx, tracerEvents)
+	if len(errs) > 0 {
+		b.recordFailure()
+	} else {
+		b.recordSuccess()
+	}
+	return errs
+}
+
+func (t *circuitBreakerTransport) AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error {
+	b := t.breakerFor("AddTracerEventBatch")
+	if !b.allow() {
+		return []error{&circuitBreakerErr{op: "AddTracerEventBatch"}}
+	}
+
+	errs := t.next.AddTracerEventBatch(ctx, events)
+	if len(errs) > 0 {
+		b.recordFailure()
+	} else {
+		b.recordSuccess()
+	}
+	return errs
+}
+
+func (t *circuitBreakerTransport) AddLabel(ctx context.Context, label types.Label) error {
+	return t.guard("AddLabel", func() error { return t.next.AddLabel(ctx, label) })
+}
+
+func (t *circuitBreakerTransport) GetLabels(ctx context.Context) ([]types.Label, error) {
+	var ret []types.Label
+	err := t.guard("GetLabels", func() error {
+		var innerErr error
+		ret, innerErr = t.next.GetLabels(ctx)
+		return innerErr
+	})
+	return ret, err
+}
+
+func (t *circuitBreakerTransport) GetLabel(ctx context.Context, ID int) (types.Label, error) {
+	var ret types.Label
+	err := t.guard("GetLabel", func() error {
+		var innerErr error
+		ret, innerErr = t.next.GetLabel(ctx, ID)
+		return innerErr
+	})
+	return ret, err
+}
+
+/*SubscribeTracers and SubscribeEvents guard only the connection attempt, not the
+ * (potentially very long) lifetime of the resulting stream, since a breaker is
+ * meant to stop hammering a server that's already down, not to cut off a
+ * healthy subscription. */
+func (t *circuitBreakerTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	b := t.breakerFor("SubscribeTracers")
+	if !b.allow() {
+		return nil, &circuitBreakerErr{op: "SubscribeTracers"}
+	}
+
+	ch, err := t.next.SubscribeTracers(ctx, lastSeenID)
+	if err != nil {
+		b.recordFailure()
+	} else {
+		b.recordSuccess()
+	}
+	return ch, err
+}
+
+func (t *circuitBreakerTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	b := t.breakerFor("SubscribeEvents")
+	if !b.allow() {
+		return nil, &circuitBreakerErr{op: "SubscribeEvents"}
+	}
+
+	ch, err := t.next.SubscribeEvents(ctx, tracerID, lastSeenID)
+	if err != nil {
+		b.recordFailure()
+	} else {
+		b.recordSuccess()
+	}
+	return ch, err
+}
+
+/* --- token bucket rate limiter ---------------------------------------------- */
+
+/*tokenBucket caps outbound QPS toward the tracer-server, shared across every
+ * endpoint so a burst on one call type still counts against the same budget. */
+type tokenBucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	refillRate float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(qps float64) *tokenBucket {
+	return &tokenBucket{tokens: qps, maxTokens: qps, refillRate: qps, lastRefill: time.Now()}
+}
+
+/*wait blocks until a token is available or ctx is cancelled. */
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		elapsed := now.Sub(b.lastRefill).Seconds()
+		b.tokens = minFloat(b.maxTokens, b.tokens+elapsed*b.refillRate)
+		b.lastRefill = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+type rateLimiterTransport struct {
+	next   Transport
+	bucket *tokenBucket
+}
+
+func newRateLimiterTransport(next Transport, qps float64) Transport {
+	return &rateLimiterTransport{next: next, bucket: newTokenBucket(qps)}
+}
+
+func (t *rateLimiterTransport) AddTracers(ctx context.Context, request types.Request) error {
+	if err := t.bucket.wait(ctx); err != nil {
+		return err
+	}
+	return t.next.AddTracers(ctx, request)
+}
+
+func (t *rateLimiterTransport) GetTracers(ctx context.Context) ([]types.Tracer, error) {
+	if err := t.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return t.next.GetTracers(ctx)
+}
+
+func (t *rateLimiterTransport) AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	if err := t.bucket.wait(ctx); err != nil {
+		return err
+	}
+	return t.next.AddTracerEvent(ctx, tracerEvent, tracerID)
+}
+
+func (t *rateLimiterTransport) AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	if err := t.bucket.wait(ctx); err != nil {
+		return []error{err}
+	}
+	return t.next.AddTracerEvents(ctx, tracerEvents)
+}
+
+func (t *rateLimiterTransport) AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error {
+	if err := t.bucket.wait(ctx); err != nil {
+		return []error{err}
+	}
+	return t.next.AddTracerEventBatch(ctx, events)
+}
+
+func (t *rateLimiterTransport) AddLabel(ctx context.Context, label types.Label) error {
+	if err := t.bucket.wait(ctx); err != nil {
+		return err
+	}
+	return t.next.AddLabel(ctx, label)
+}
+
+func (t *rateLimiterTransport) GetLabels(ctx context.Context) ([]types.Label, error) {
+	if err := t.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return t.next.GetLabels(ctx)
+}
+
+func (t *rateLimiterTransport) GetLabel(ctx context.Context, ID int) (types.Label, error) {
+	if err := t.bucket.wait(ctx); err != nil {
+		return types.Label{}, err
+	}
+	return t.next.GetLabel(ctx, ID)
+}
+
+/*SubscribeTracers and SubscribeEvents only rate-limit opening the connection,
+ * not the events it goes on to deliver. */
+func (t *rateLimiterTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	if err := t.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return t.next.SubscribeTracers(ctx, lastSeenID)
+}
+
+func (t *rateLimiterTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	if err := t.bucket.wait(ctx); err != nil {
+		return nil, err
+	}
+	return t.next.SubscribeEvents(ctx, tracerID, lastSeenID)
+}
+
+/* --- distributed tracing instrumentation ------------------------------------ */
+
+/*tracingTransport starts an OpenTracing span around every call and leaves it in
+ * ctx, so httpTransport and grpcTransport can inject the span context into
+ * outbound headers/metadata and a Jaeger/Zipkin backend can correlate the hop
+ * with the calling proxy. */
+type tracingTransport struct {
+	next Transport
+}
+
+func newTracingTransport(next Transport) Transport {
+	return &tracingTransport{next: next}
+}
+
+func (t *tracingTransport) traced(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	span, spanCtx := startSpanFromContext(ctx, op)
+	defer span.finish()
+
+	err := fn(spanCtx)
+	if err != nil {
+		span.setError(err)
+		log.Trace.Printf("%s span recorded error: %s", op, err.Error())
+	}
+	return err
+}
+
+func (t *tracingTransport) AddTracers(ctx context.Context, request types.Request) error {
+	return t.traced(ctx, "AddTracers", func(ctx context.Context) error { return t.next.AddTracers(ctx, request) })
+}
+
+func (t *tracingTransport) GetTracers(ctx context.Context) ([]types.Tracer, error) {
+	var ret []types.Tracer
+	err := t.traced(ctx, "GetTracers", func(ctx context.Context) error {
+		var innerErr error
+		ret, innerErr = t.next.GetTracers(ctx)
+		return innerErr
+	})
+	return ret, err
+}
+
+func (t *tracingTransport) AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	return t.traced(ctx, "AddTracerEvent", func(ctx context.Context) error {
+		return t.next.AddTracerEvent(ctx, tracerEvent, tracerID)
+	})
+}
+
+func (t *tracingTransport) AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	var ret []error
+	_ = t.traced(ctx, "AddTracerEvents", func(ctx context.Context) error {
+		ret = t.next.AddTracerEvents(ctx, tracerEvents)
+		if len(ret) > 0 {
+			return ret[0]
+		}
+		return nil
+	})
+	return ret
+}
+
+func (t *tracingTransport) AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error {
+	var ret []error
+	_ = t.traced(ctx, "AddTracerEventBatch", func(ctx context.Context) error {
+		ret = t.next.AddTracerEventBatch(ctx, events)
+		if len(ret) > 0 {
+			return ret[0]
+		}
+		return nil
+	})
+	return ret
+}
+
+func (t *tracingTransport) AddLabel(ctx context.Context, label types.Label) error {
+	return t.traced(ctx, "AddLabel", func(ctx context.Context) error { return t.next.AddLabel(ctx, label) })
+}
+
+func (t *tracingTransport) GetLabels(ctx context.Context) ([]types.Label, error) {
+	var ret []types.Label
+	err := t.traced(ctx, "GetLabels", func(ctx context.Context) error {
+		var innerErr error
+		ret, innerErr = t.next.GetLabels(ctx)
+		return innerErr
+	})
+	return ret, err
+}
+
+func (t *tracingTransport) GetLabel(ctx context.Context, ID int) (types.Label, error) {
+	var ret types.Label
+	err := t.traced(ctx, "GetLabel", func(ctx context.Context) error {
+		var innerErr error
+		ret, innerErr = t.next.GetLabel(ctx, ID)
+		return innerErr
+	})
+	return ret, err
+}
+
+/*SubscribeTracers and SubscribeEvents span only the handshake that opens the
+ * connection; the stream it returns can outlive any one span by hours. */
+func (t *tracingTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	var ch <-chan TracerUpdate
+	err := t.traced(ctx, "SubscribeTracers", func(ctx context.Context) error {
+		var innerErr error
+		ch, innerErr = t.next.SubscribeTracers(ctx, lastSeenID)
+		return innerErr
+	})
+	return ch, err
+}
+
+func (t *tracingTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	var ch <-chan types.TracerEvent
+	err := t.traced(ctx, "SubscribeEvents", func(ctx context.Context) error {
+		var innerErr error
+		ch, innerErr = t.next.SubscribeEvents(ctx, tracerID, lastSeenID)
+		return innerErr
+	})
+	return ch, err
+}
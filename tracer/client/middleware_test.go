@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"tracy/tracer/types"
+)
+
+func TestEndpointBreakerTripsAfterThreshold(t *testing.T) {
+	b := &endpointBreaker{threshold: 2, halfOpenAfter: time.Hour}
+
+	if !b.allow() {
+		t.Fatalf("expected first call to be allowed while closed")
+	}
+	b.recordFailure()
+	if !b.allow() {
+		t.Fatalf("expected call to be allowed before threshold is reached")
+	}
+	b.recordFailure()
+
+	if b.allow() {
+		t.Fatalf("expected breaker to reject calls once open")
+	}
+}
+
+func TestEndpointBreakerHalfOpenAllowsOnlyOneProbe(t *testing.T) {
+	b := &endpointBreaker{threshold: 1, halfOpenAfter: time.Millisecond}
+	b.recordFailure()
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatalf("expected the first caller after the half-open timeout to be let through")
+	}
+	if b.allow() {
+		t.Fatalf("expected concurrent callers to be rejected while a half-open probe is in flight")
+	}
+
+	b.recordFailure()
+	if b.allow() {
+		t.Fatalf("expected breaker to reopen after a failed probe")
+	}
+}
+
+func TestEndpointBreakerHalfOpenRecoversOnSuccess(t *testing.T) {
+	b := &endpointBreaker{threshold: 1, halfOpenAfter: time.Millisecond}
+	b.recordFailure()
+	time.Sleep(5 * time.Millisecond)
+
+	if !b.allow() {
+		t.Fatalf("expected probe to be let through")
+	}
+	b.recordSuccess()
+
+	if !b.allow() {
+		t.Fatalf("expected breaker to be closed again after a successful probe")
+	}
+	if !b.allow() {
+		t.Fatalf("expected a closed breaker to allow more than one concurrent call")
+	}
+}
+
+func TestCircuitBreakerTransportRejectsWhileOpen(t *testing.T) {
+	fake := &fakeTransport{}
+	fake.setFail(true)
+
+	transport := newCircuitBreakerTransport(fake)
+	for i := 0; i < defaultCircuitBreakerThreshold; i++ {
+		transport.AddTracers(context.Background(), types.Request{})
+	}
+
+	callsBeforeOpen := fake.calls
+	err := transport.AddTracers(context.Background(), types.Request{})
+	if _, ok := err.(*circuitBreakerErr); !ok {
+		t.Fatalf("expected a circuitBreakerErr once tripped, got %v (%T)", err, err)
+	}
+	if fake.calls != callsBeforeOpen {
+		t.Fatalf("expected the underlying transport not to be called while the breaker is open")
+	}
+}
+
+func TestTokenBucketWaitAllowsCallsWithinBudget(t *testing.T) {
+	bucket := newTokenBucket(1000) // starts with 1000 tokens, so none of the 10 calls has to wait
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		if err := bucket.wait(ctx); err != nil {
+			t.Fatalf("wait: %s", err)
+		}
+	}
+}
+
+func TestTokenBucketWaitRespectsCancellation(t *testing.T) {
+	bucket := &tokenBucket{tokens: 0, maxTokens: 1, refillRate: 0.001, lastRefill: time.Now()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := bucket.wait(ctx); err == nil {
+		t.Fatalf("expected wait to return ctx's error once cancelled")
+	}
+}
+
+/*TestWrapWithMiddlewareCircuitBreakerIsOutermost builds the same stack
+ * wrapWithMiddleware does (circuit breaker outermost, then rate limiter, then
+ * tracing innermost) directly, and checks that once the breaker trips, a call
+ * routed through all three layers is rejected without the rate limiter or the
+ * fake transport underneath ever seeing it. */
+func TestWrapWithMiddlewareCircuitBreakerIsOutermost(t *testing.T) {
+	fake := &fakeTransport{}
+	fake.setFail(true)
+
+	traced := newTracingTransport(fake)
+	rateLimited := newRateLimiterTransport(traced, 1000)
+	breaker := newCircuitBreakerTransport(rateLimited)
+
+	for i := 0; i < defaultCircuitBreakerThreshold; i++ {
+		breaker.AddTracers(context.Background(), types.Request{})
+	}
+
+	callsBeforeOpen := fake.calls
+	err := breaker.AddTracers(context.Background(), types.Request{})
+	if _, ok := err.(*circuitBreakerErr); !ok {
+		t.Fatalf("expected a circuitBreakerErr once tripped, got %v (%T)", err, err)
+	}
+	if fake.calls != callsBeforeOpen {
+		t.Fatalf("expected the inner transport not to be reached once the outer breaker trips")
+	}
+}
@@ -0,0 +1,305 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tracy/log"
+	"tracy/tracer/types"
+)
+
+/* EventSink config keys, mirroring the "every key defaults to its inert value"
+ * convention from middleware.go: with none of these set, newEventSink behaves
+ * like a small, short-lived buffer rather than changing overall behavior. */
+const (
+	configEventSinkEnabled         = "tracer-client-event-sink-enabled"
+	configEventSinkBatchSize       = "tracer-client-event-batch-size"
+	configEventSinkFlushIntervalMs = "tracer-client-event-flush-interval-ms"
+	configEventSinkQueueCapacity   = "tracer-client-event-queue-capacity"
+	configEventSinkBlockOnFull     = "tracer-client-event-block-on-full"
+
+	defaultEventBatchSize     = 50
+	defaultEventFlushInterval = 2 * time.Second
+	defaultEventQueueCapacity = 1000
+)
+
+var (
+	eventSinkOnce sync.Once
+	theEventSink  *EventSink
+)
+
+/*activeEventSink returns the package-wide EventSink that AddTracerEventContext
+ * and AddTracerEventsContext push into, or nil if "tracer-client-event-sink-enabled"
+ * isn't set, in which case those callers fall back to posting synchronously the
+ * way they always have. */
+func activeEventSink() *EventSink {
+	eventSinkOnce.Do(func() {
+		if configBool(configEventSinkEnabled) {
+			theEventSink = newEventSink(activeTransport())
+		}
+	})
+	return theEventSink
+}
+
+/*QueueFullError is returned by EventSink.Push when the queue is full and
+ * "tracer-client-event-block-on-full" isn't set, so the caller's event is
+ * dropped rather than blocking a proxy mid-request. */
+type QueueFullError struct {
+	TracerID int
+}
+
+func (e *QueueFullError) Error() string {
+	return "event sink queue is full, dropping tracer event"
+}
+
+/*SinkClosedError is returned by EventSink.Push once Close has been called. */
+type SinkClosedError struct{}
+
+func (e *SinkClosedError) Error() string {
+	return "event sink is closed"
+}
+
+type queuedEvent struct {
+	tracerID int
+	event    types.TracerEvent
+}
+
+/*sinkMsg is either a queued event or a flush request, sent over the same
+ * channel so a Flush can never jump ahead of events pushed before it. */
+type sinkMsg struct {
+	event *queuedEvent
+	flush chan struct{}
+}
+
+/*EventSink buffers tracer events in memory and flushes them to a Transport in
+ * batches from a single background goroutine, coalescing whatever arrived
+ * since the last flush instead of issuing one network call per event. It's
+ * built to sit in front of the configured Transport: AddTracerEventContext and
+ * AddTracerEventsContext push into it instead of calling the Transport
+ * directly once it's enabled. */
+type EventSink struct {
+	next Transport
+
+	in            chan sinkMsg
+	batchSize     int
+	flushInterval time.Duration
+	blockOnFull   bool
+
+	dropped uint64
+	closed  int32
+
+	closeOnce sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+/*newEventSink creates an EventSink that flushes onto next and starts its
+ * background flush loop. Batch size, flush interval, queue capacity, and
+ * whether to block when the queue is full all come from configure, falling
+ * back to sane defaults. */
+func newEventSink(next Transport) *EventSink {
+	s := &EventSink{
+		next:          next,
+		in:            make(chan sinkMsg, configuredEventQueueCapacity()),
+		batchSize:     configuredEventBatchSize(),
+		flushInterval: configuredEventFlushInterval(),
+		blockOnFull:   configBool(configEventSinkBlockOnFull),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func configuredEventBatchSize() int {
+	if n := int(configFloat(configEventSinkBatchSize)); n > 0 {
+		return n
+	}
+	return defaultEventBatchSize
+}
+
+func configuredEventFlushInterval() time.Duration {
+	if ms := configFloat(configEventSinkFlushIntervalMs); ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	return defaultEventFlushInterval
+}
+
+func configuredEventQueueCapacity() int {
+	if n := int(configFloat(configEventSinkQueueCapacity)); n > 0 {
+		return n
+	}
+	return defaultEventQueueCapacity
+}
+
+/*Push queues tracerEvent for tracerID to be flushed in a later batch. When the
+ * queue is full, Push either blocks until ctx is cancelled or room frees up
+ * (if "tracer-client-event-block-on-full" is set) or drops the event and
+ * returns a *QueueFullError while bumping the Dropped counter (the default,
+ * so a slow tracer-server can't stall the proxy request the event came from).
+ * Once Close has been called, Push returns a *SinkClosedError without queueing
+ * anything. */
+func (s *EventSink) Push(ctx context.Context, tracerID int, tracerEvent types.TracerEvent) error {
+	if atomic.LoadInt32(&s.closed) != 0 {
+		return &SinkClosedError{}
+	}
+
+	msg := sinkMsg{event: &queuedEvent{tracerID: tracerID, event: tracerEvent}}
+
+	if s.blockOnFull {
+		select {
+		case s.in <- msg:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.stop:
+			return &SinkClosedError{}
+		}
+	}
+
+	select {
+	case s.in <- msg:
+		return nil
+	case <-s.stop:
+		return &SinkClosedError{}
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+		log.Warning.Printf("event sink queue full, dropping tracer event for tracer %d", tracerID)
+		return &QueueFullError{TracerID: tracerID}
+	}
+}
+
+/*Dropped reports how many events Push has dropped because the queue was full,
+ * exposed as the sink's one metric since this package doesn't otherwise wire
+ * up to a metrics backend. */
+func (s *EventSink) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+/*Flush blocks until every event queued before this call has been handed to the
+ * underlying Transport at least once, or ctx is cancelled. */
+func (s *EventSink) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case s.in <- sinkMsg{flush: done}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*Close flushes whatever's left in the queue and stops the background flush
+ * loop. It's idempotent: calling it more than once just waits on the same
+ * shutdown. Callers should invoke Close during graceful shutdown so queued
+ * events aren't silently lost on process exit. Close signals the background
+ * goroutine via s.stop rather than closing s.in, so a Push racing with Close
+ * can never send on a closed channel. */
+func (s *EventSink) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		atomic.StoreInt32(&s.closed, 1)
+		close(s.stop)
+	})
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+/*run is the sink's single background goroutine. It accumulates queued events
+ * and flushes them as one AddTracerEventBatch call whenever a batch fills up,
+ * the flush interval ticks, a Flush request arrives, or the sink is closed. */
+func (s *EventSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	pending := make([]queuedEvent, 0, s.batchSize)
+	flush := func() {
+		if len(pending) == 0 {
+			return
+		}
+		s.sendBatch(pending)
+		pending = pending[:0]
+	}
+
+	for {
+		select {
+		case msg := <-s.in:
+			if msg.flush != nil {
+				flush()
+				close(msg.flush)
+				continue
+			}
+			pending = append(pending, *msg.event)
+			if len(pending) >= s.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-s.stop:
+			s.drain(&pending)
+			flush()
+			return
+		}
+	}
+}
+
+/*drain appends every message already sitting in s.in to pending without
+ * blocking, so Close's final flush includes whatever was queued up to the
+ * moment it was called. */
+func (s *EventSink) drain(pending *[]queuedEvent) {
+	for {
+		select {
+		case msg := <-s.in:
+			if msg.flush != nil {
+				close(msg.flush)
+				continue
+			}
+			*pending = append(*pending, *msg.event)
+		default:
+			return
+		}
+	}
+}
+
+/*sendBatch hands pending to the underlying Transport, retrying with the same
+ * exponential backoff policy ("tracer-server-max-retries", see http.go's
+ * configuredRetryPolicy) used for every other retried call before giving up
+ * and logging. */
+func (s *EventSink) sendBatch(pending []queuedEvent) {
+	events := make([]BatchedEvent, len(pending))
+	for i, p := range pending {
+		events[i] = BatchedEvent{TracerID: p.tracerID, Event: p.event}
+	}
+
+	policy := configuredRetryPolicy()
+	ctx := context.Background()
+
+	var errs []error
+	for attempt := 0; attempt < policy.maxAttempts; attempt++ {
+		errs = s.next.AddTracerEventBatch(ctx, events)
+		if len(errs) == 0 {
+			return
+		}
+		if attempt < policy.maxAttempts-1 {
+			log.Warning.Printf("flushing %d tracer events failed (attempt %d/%d), retrying", len(events), attempt+1, policy.maxAttempts)
+			time.Sleep(policy.backoff(attempt))
+		}
+	}
+
+	for _, err := range errs {
+		log.Warning.Printf("giving up flushing tracer event batch: %s", err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"tracy/tracer/types"
+)
+
+func newTestEventSink(next Transport) *EventSink {
+	return &EventSink{
+		next:          next,
+		in:            make(chan sinkMsg, 16),
+		batchSize:     4,
+		flushInterval: time.Hour, // tests trigger flushes explicitly
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+func startTestEventSink(next Transport) *EventSink {
+	s := newTestEventSink(next)
+	go s.run()
+	return s
+}
+
+/*TestEventSinkCloseRacesWithPush is a regression test for a shutdown race where
+ * Close used to close s.in directly, which could panic a concurrent Push
+ * sending on the same channel. Close now signals shutdown via s.stop instead,
+ * so Push and Close racing against each other should never panic, and every
+ * Push should return cleanly with either nil or a *SinkClosedError. */
+func TestEventSinkCloseRacesWithPush(t *testing.T) {
+	fake := &fakeTransport{}
+	s := startTestEventSink(fake)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			err := s.Push(context.Background(), id, types.TracerEvent{})
+			if err != nil {
+				if _, ok := err.(*SinkClosedError); !ok {
+					t.Errorf("Push returned unexpected error: %s", err)
+				}
+			}
+		}(i)
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	wg.Wait()
+
+	if err := s.Push(context.Background(), 0, types.TracerEvent{}); err == nil {
+		t.Fatalf("expected Push after Close to return SinkClosedError")
+	} else if _, ok := err.(*SinkClosedError); !ok {
+		t.Fatalf("expected SinkClosedError, got %T", err)
+	}
+}
+
+func TestEventSinkCloseIsIdempotent(t *testing.T) {
+	s := startTestEventSink(&fakeTransport{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Close(context.Background()); err != nil {
+				t.Errorf("Close: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestEventSinkDrainFlushesQueuedEventsOnClose(t *testing.T) {
+	fake := &fakeTransport{}
+	s := newTestEventSink(fake)
+	// Queue events directly without starting run(), so they're still sitting
+	// in s.in when Close triggers drain().
+	for i := 0; i < 3; i++ {
+		s.in <- sinkMsg{event: &queuedEvent{tracerID: i, event: types.TracerEvent{}}}
+	}
+	go s.run()
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %s", err)
+	}
+	if fake.calls == 0 {
+		t.Fatalf("expected drain to flush the queued events through AddTracerEventBatch before shutdown")
+	}
+}
+
+func TestEventSinkFlushWaitsForPendingEvents(t *testing.T) {
+	fake := &fakeTransport{}
+	s := startTestEventSink(fake)
+	defer s.Close(context.Background())
+
+	if err := s.Push(context.Background(), 1, types.TracerEvent{}); err != nil {
+		t.Fatalf("Push: %s", err)
+	}
+	if err := s.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+	if fake.calls == 0 {
+		t.Fatalf("expected Flush to have sent the pending event")
+	}
+}
+
+func TestEventSinkPushDropsWhenQueueFullAndNotBlocking(t *testing.T) {
+	s := &EventSink{
+		next:      &fakeTransport{},
+		in:        make(chan sinkMsg), // unbuffered, and nobody's draining it
+		batchSize: 1,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	err := s.Push(context.Background(), 1, types.TracerEvent{})
+	if _, ok := err.(*QueueFullError); !ok {
+		t.Fatalf("expected QueueFullError, got %v (%T)", err, err)
+	}
+	if s.Dropped() != 1 {
+		t.Fatalf("expected Dropped() == 1, got %d", s.Dropped())
+	}
+}
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"tracy/log"
+	"tracy/tracer/types"
+)
+
+/*SubscribeTracers opens a long-lived, push-based subscription to tracer
+ * creations and updates, so UI consumers and external integrations don't have
+ * to re-poll GetTracers. It reconnects with exponential backoff on a dropped
+ * connection, resuming from the last update ID it saw so a reconnect neither
+ * misses nor repeats deltas. The returned channel is closed once ctx is
+ * cancelled. */
+func SubscribeTracers(ctx context.Context) (<-chan TracerUpdate, error) {
+	stream, err := activeTransport().SubscribeTracers(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan TracerUpdate)
+	go func() {
+		defer close(out)
+
+		lastSeenID := 0
+		attempt := 0
+		policy := configuredRetryPolicy()
+
+		for {
+			update, ok := <-stream
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+
+				log.Warning.Printf("SubscribeTracers: disconnected, reconnecting from update %d", lastSeenID)
+				select {
+				case <-time.After(policy.backoff(attempt)):
+				case <-ctx.Done():
+					return
+				}
+				attempt++
+
+				next, err := activeTransport().SubscribeTracers(ctx, lastSeenID)
+				if err != nil {
+					log.Warning.Printf("SubscribeTracers: reconnect failed: %s", err.Error())
+					continue
+				}
+				stream = next
+				continue
+			}
+
+			attempt = 0
+			lastSeenID = update.ID
+			select {
+			case out <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+/*SubscribeEvents opens a long-lived, push-based subscription to a single
+ * tracer's events, reconnecting and resuming the same way SubscribeTracers
+ * does. The returned channel is closed once ctx is cancelled. */
+func SubscribeEvents(ctx context.Context, tracerID int) (<-chan types.TracerEvent, error) {
+	stream, err := activeTransport().SubscribeEvents(ctx, tracerID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.TracerEvent)
+	go func() {
+		defer close(out)
+
+		lastSeenID := 0
+		attempt := 0
+		policy := configuredRetryPolicy()
+
+		for {
+			event, ok := <-stream
+			if !ok {
+				if ctx.Err() != nil {
+					return
+				}
+
+				log.Warning.Printf("SubscribeEvents: tracer %d disconnected, reconnecting from event %d", tracerID, lastSeenID)
+				select {
+				case <-time.After(policy.backoff(attempt)):
+				case <-ctx.Done():
+					return
+				}
+				attempt++
+
+				next, err := activeTransport().SubscribeEvents(ctx, tracerID, lastSeenID)
+				if err != nil {
+					log.Warning.Printf("SubscribeEvents: reconnect failed: %s", err.Error())
+					continue
+				}
+				stream = next
+				continue
+			}
+
+			attempt = 0
+			lastSeenID = event.ID
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
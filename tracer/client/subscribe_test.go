@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"tracy/tracer/types"
+)
+
+/*reconnectTransport is a fakeTransport whose SubscribeTracers/SubscribeEvents
+ * close their channel after emitting one update, so tests can observe
+ * SubscribeTracers/SubscribeEvents reconnecting and resuming from lastSeenID. */
+type reconnectTransport struct {
+	fakeTransport
+	attempts int32
+}
+
+func (r *reconnectTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	n := atomic.AddInt32(&r.attempts, 1)
+	ch := make(chan TracerUpdate, 1)
+	ch <- TracerUpdate{ID: lastSeenID + 1, Tracer: types.Tracer{ID: lastSeenID + 1}}
+	if n < 3 {
+		close(ch)
+	}
+	return ch, nil
+}
+
+func (r *reconnectTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	n := atomic.AddInt32(&r.attempts, 1)
+	ch := make(chan types.TracerEvent, 1)
+	ch <- types.TracerEvent{ID: lastSeenID + 1, TracerID: tracerID}
+	if n < 3 {
+		close(ch)
+	}
+	return ch, nil
+}
+
+func withActiveTransport(t *testing.T, transport Transport) {
+	t.Helper()
+	prev := activeTransport()
+	SetTransport(transport)
+	t.Cleanup(func() { SetTransport(prev) })
+}
+
+func TestSubscribeTracersReconnectsAndResumesFromLastSeenID(t *testing.T) {
+	fake := &reconnectTransport{}
+	withActiveTransport(t, fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	updates, err := SubscribeTracers(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeTracers: %s", err)
+	}
+
+	var seen []int
+	for len(seen) < 3 {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				t.Fatalf("channel closed early, saw %d updates", len(seen))
+			}
+			seen = append(seen, u.ID)
+		case <-ctx.Done():
+			t.Fatalf("timed out after seeing %d updates", len(seen))
+		}
+	}
+
+	for i, id := range seen {
+		if id != i+1 {
+			t.Fatalf("expected update %d to carry ID %d (resuming from lastSeenID), got %d", i, i+1, id)
+		}
+	}
+}
+
+func TestSubscribeEventsReconnectsAndResumesFromLastSeenID(t *testing.T) {
+	fake := &reconnectTransport{}
+	withActiveTransport(t, fake)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	events, err := SubscribeEvents(ctx, 42)
+	if err != nil {
+		t.Fatalf("SubscribeEvents: %s", err)
+	}
+
+	var seen []int
+	for len(seen) < 3 {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("channel closed early, saw %d events", len(seen))
+			}
+			seen = append(seen, e.ID)
+		case <-ctx.Done():
+			t.Fatalf("timed out after seeing %d events", len(seen))
+		}
+	}
+
+	for i, id := range seen {
+		if id != i+1 {
+			t.Fatalf("expected event %d to carry ID %d (resuming from lastSeenID), got %d", i, i+1, id)
+		}
+	}
+}
+
+func TestSubscribeTracersClosesOutputWhenContextCancelled(t *testing.T) {
+	fake := &reconnectTransport{}
+	withActiveTransport(t, fake)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, err := SubscribeTracers(ctx)
+	if err != nil {
+		t.Fatalf("SubscribeTracers: %s", err)
+	}
+
+	<-updates // drain the first update so the goroutine reaches its next select
+	cancel()
+
+	select {
+	case _, ok := <-updates:
+		if ok {
+			// a second buffered update may still be in flight; drain until closed
+			for ok {
+				_, ok = <-updates
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the output channel to close once ctx is cancelled")
+	}
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+/*clientSpan wraps an OpenTracing span so the rest of the package doesn't need to
+ * import opentracing-go directly. */
+type clientSpan struct {
+	span opentracing.Span
+}
+
+func (s clientSpan) finish() {
+	s.span.Finish()
+}
+
+func (s clientSpan) setError(err error) {
+	ext.Error.Set(s.span, true)
+	s.span.LogKV("error.message", err.Error())
+}
+
+/*startSpanFromContext starts a child span for op under whatever tracer is
+ * registered as opentracing.GlobalTracer() (a no-op tracer if the host
+ * application never configured one, making this inert by default) and returns a
+ * context carrying it so it can be injected into outbound headers/metadata. */
+func startSpanFromContext(ctx context.Context, op string) (clientSpan, context.Context) {
+	span, spanCtx := opentracing.StartSpanFromContext(ctx, "tracy.client."+op)
+	ext.SpanKindRPCClient.Set(span)
+	return clientSpan{span: span}, spanCtx
+}
+
+/*injectHTTPHeaders writes the span (if any) found on ctx into outbound HTTP
+ * headers, so tracer-server hops can be correlated with the calling proxy in a
+ * Jaeger/Zipkin backend. It's a no-op if no span is active. */
+func injectHTTPHeaders(ctx context.Context, header opentracing.HTTPHeadersCarrier) {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	_ = span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, header)
+}
+
+/*tracingUnaryInterceptor is a gRPC unary client interceptor that injects the span
+ * (if any) found on ctx into outbound gRPC metadata, mirroring injectHTTPHeaders
+ * for the gRPC transport. It's a no-op if no span is active. */
+func tracingUnaryInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span != nil {
+		md := metadata.MD{}
+		if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, metadataTextMapCarrier{md}); err == nil {
+			ctx = metadata.NewOutgoingContext(ctx, md)
+		}
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+/*metadataTextMapCarrier adapts grpc metadata.MD to opentracing's TextMapWriter so
+ * a span context can be injected into outbound gRPC metadata. */
+type metadataTextMapCarrier struct {
+	md metadata.MD
+}
+
+func (c metadataTextMapCarrier) Set(key, val string) {
+	c.md.Set(key, val)
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"tracy/configure"
+	"tracy/tracer/types"
+)
+
+/*Transport is the set of operations a tracer-server backend must support. The
+ * request functions in this package (AddTracers, GetTracers, ...) are thin
+ * wrappers around whichever Transport is selected by the "tracer-transport"
+ * config key, so the rest of tracy doesn't need to know whether it's talking
+ * HTTP+JSON or gRPC. */
+type Transport interface {
+	AddTracers(ctx context.Context, request types.Request) error
+	GetTracers(ctx context.Context) ([]types.Tracer, error)
+	AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error
+	AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error
+	AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error
+	AddLabel(ctx context.Context, label types.Label) error
+	GetLabels(ctx context.Context) ([]types.Label, error)
+	GetLabel(ctx context.Context, ID int) (types.Label, error)
+	// SubscribeTracers and SubscribeEvents open one long-lived subscription
+	// attempt each, starting after lastSeenID, and close the returned channel
+	// when the underlying connection ends (error, server close, or ctx
+	// cancellation). The resumable, auto-reconnecting API consumers actually
+	// want lives at the package level (see subscribe.go) and is built on top
+	// of these.
+	SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error)
+	SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error)
+}
+
+/*BatchedEvent pairs a tracer event with the tracer ID it belongs to. EventSink
+ * (see sink.go) accumulates these across however many tracers a page load
+ * touches and hands a Transport the whole slice in one AddTracerEventBatch
+ * call, instead of one AddTracerEvents call per tracer ID. */
+type BatchedEvent struct {
+	TracerID int
+	Event    types.TracerEvent
+}
+
+/*TracerUpdate is one delta from a SubscribeTracers stream: a tracer that was
+ * created or changed, tagged with the update ID a caller passes back in as
+ * lastSeenID to resume after a reconnect without missing or repeating deltas. */
+type TracerUpdate struct {
+	Tracer types.Tracer `json:"tracer"`
+	ID     int          `json:"id"`
+}
+
+var (
+	transportMu      sync.Mutex
+	defaultTransport Transport
+)
+
+/*activeTransport returns the package-wide Transport selected by the
+ * "tracer-transport" config key ("http" or "grpc"), defaulting to the HTTP+JSON
+ * transport that's always been here when the key is absent. */
+func activeTransport() Transport {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+
+	if defaultTransport == nil {
+		defaultTransport = newTransportFromConfig()
+	}
+	return defaultTransport
+}
+
+/*SetTransport overrides the package-wide Transport, bypassing the usual
+ * "tracer-transport" config lookup and the middleware (see middleware.go) that
+ * newTransportFromConfig wraps around it; t is used exactly as given. This
+ * package's tests use it to stub the transport out. */
+func SetTransport(t Transport) {
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	defaultTransport = t
+}
+
+func newTransportFromConfig() Transport {
+	transportName := "http"
+	if raw, err := configure.ReadConfig("tracer-transport"); err == nil {
+		if name, ok := raw.(string); ok && name != "" {
+			transportName = name
+		}
+	}
+
+	switch transportName {
+	case "grpc":
+		return wrapWithMiddleware(newGRPCTransport())
+	default:
+		return wrapWithMiddleware(newHTTPTransport())
+	}
+}
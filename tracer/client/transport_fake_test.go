@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+
+	"tracy/tracer/types"
+)
+
+/*fakeTransport is a minimal, configurable Transport test double shared by this
+ * package's tests: callers set fail to control whether calls error, and read
+ * calls to assert how many times the underlying Transport was actually
+ * invoked through whatever middleware wraps it. */
+type fakeTransport struct {
+	fail  int32
+	calls int32
+}
+
+func (f *fakeTransport) setFail(v bool) {
+	n := int32(0)
+	if v {
+		n = 1
+	}
+	atomic.StoreInt32(&f.fail, n)
+}
+
+func (f *fakeTransport) failing() bool {
+	return atomic.LoadInt32(&f.fail) != 0
+}
+
+func (f *fakeTransport) err(op string) error {
+	if f.failing() {
+		return &NetworkError{Op: op, Err: errFake}
+	}
+	return nil
+}
+
+var errFake = &NetworkError{Op: "fake", Err: fakeErr{}}
+
+type fakeErr struct{}
+
+func (fakeErr) Error() string { return "fake transport failure" }
+
+func (f *fakeTransport) AddTracers(ctx context.Context, request types.Request) error {
+	atomic.AddInt32(&f.calls, 1)
+	return f.err("AddTracers")
+}
+
+func (f *fakeTransport) GetTracers(ctx context.Context) ([]types.Tracer, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return nil, f.err("GetTracers")
+}
+
+func (f *fakeTransport) AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	atomic.AddInt32(&f.calls, 1)
+	return f.err("AddTracerEvent")
+}
+
+func (f *fakeTransport) AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	atomic.AddInt32(&f.calls, 1)
+	if err := f.err("AddTracerEvents"); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+func (f *fakeTransport) AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error {
+	atomic.AddInt32(&f.calls, 1)
+	if err := f.err("AddTracerEventBatch"); err != nil {
+		return []error{err}
+	}
+	return nil
+}
+
+func (f *fakeTransport) AddLabel(ctx context.Context, label types.Label) error {
+	atomic.AddInt32(&f.calls, 1)
+	return f.err("AddLabel")
+}
+
+func (f *fakeTransport) GetLabels(ctx context.Context) ([]types.Label, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return nil, f.err("GetLabels")
+}
+
+func (f *fakeTransport) GetLabel(ctx context.Context, ID int) (types.Label, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return types.Label{}, f.err("GetLabel")
+}
+
+func (f *fakeTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if err := f.err("SubscribeTracers"); err != nil {
+		return nil, err
+	}
+	ch := make(chan TracerUpdate)
+	close(ch)
+	return ch, nil
+}
+
+func (f *fakeTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	atomic.AddInt32(&f.calls, 1)
+	if err := f.err("SubscribeEvents"); err != nil {
+		return nil, err
+	}
+	ch := make(chan types.TracerEvent)
+	close(ch)
+	return ch, nil
+}
@@ -0,0 +1,337 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"tracy/log"
+	"tracy/tracer/tracerpb"
+	"tracy/tracer/types"
+)
+
+/*grpcTransport is the gRPC/protobuf Transport implementation, selected by setting
+ * the "tracer-transport" config key to "grpc". It dials the tracer-server lazily
+ * and reuses the connection (and its HTTP/2 multiplexing) across calls, the same
+ * way httpTransport reuses its shared *http.Client. */
+type grpcTransport struct {
+	connOnce sync.Once
+	conn     *grpc.ClientConn
+	connErr  error
+}
+
+func newGRPCTransport() Transport {
+	return &grpcTransport{}
+}
+
+func (t *grpcTransport) client() (tracerpb.TracerServiceClient, error) {
+	t.connOnce.Do(func() {
+		tracerServer, err := tracerServerURL()
+		if err != nil {
+			t.connErr = err
+			return
+		}
+		t.conn, t.connErr = grpc.NewClient(
+			tracerServer,
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithChainUnaryInterceptor(tracingUnaryInterceptor),
+		)
+	})
+	if t.connErr != nil {
+		return nil, t.connErr
+	}
+	return tracerpb.NewTracerServiceClient(t.conn), nil
+}
+
+/*grpcJSONCodec selects tracerpb's JSON codec for a single call via its own
+ * content-subtype, instead of that codec squatting on grpc's default "proto"
+ * name for every client/server in the process (see tracer/tracerpb/codec.go). */
+func grpcJSONCodec() grpc.CallOption {
+	return grpc.CallContentSubtype(tracerpb.JSONContentSubtype)
+}
+
+func (t *grpcTransport) AddTracers(ctx context.Context, request types.Request) error {
+	c, err := t.client()
+	if err != nil {
+		return err
+	}
+
+	tracers := make([]*tracerpb.Tracer, 0, len(request.Tracers))
+	for _, tr := range request.Tracers {
+		tracers = append(tracers, tracerToPB(tr))
+	}
+
+	_, err = c.AddTracers(ctx, &tracerpb.Request{Tracers: tracers}, grpcJSONCodec())
+	return wrapGRPCErr("AddTracers", err)
+}
+
+func (t *grpcTransport) GetTracers(ctx context.Context) ([]types.Tracer, error) {
+	c, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.GetTracers(ctx, &tracerpb.Empty{}, grpcJSONCodec())
+	if err != nil {
+		return nil, wrapGRPCErr("GetTracers", err)
+	}
+
+	ret := make([]types.Tracer, 0, len(resp.Tracers))
+	for _, tr := range resp.Tracers {
+		ret = append(ret, tracerFromPB(tr))
+	}
+	return ret, nil
+}
+
+func (t *grpcTransport) AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	c, err := t.client()
+	if err != nil {
+		return err
+	}
+
+	tracerEvent.TracerID = tracerID
+	_, err = c.AddTracerEvent(ctx, tracerEventToPB(tracerEvent), grpcJSONCodec())
+	return wrapGRPCErr("AddTracerEvent", err)
+}
+
+/*AddTracerEvents streams every event over a single client-streaming RPC instead of
+ * issuing one unary call per event, so a proxy tagging many params on a page load
+ * doesn't pay one round-trip per event. */
+func (t *grpcTransport) AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	c, err := t.client()
+	if err != nil {
+		return []error{err}
+	}
+
+	stream, err := c.AddTracerEvents(ctx, grpcJSONCodec())
+	if err != nil {
+		return []error{wrapGRPCErr("AddTracerEvents", err)}
+	}
+
+	ret := make([]error, 0)
+	for tracerID, tracerEvent := range tracerEvents {
+		tracerEvent.TracerID = tracerID
+		if err := stream.Send(tracerEventToPB(tracerEvent)); err != nil {
+			ret = append(ret, wrapGRPCErr("AddTracerEvents", err))
+		}
+	}
+
+	if _, err := stream.CloseAndRecv(); err != nil {
+		ret = append(ret, wrapGRPCErr("AddTracerEvents", err))
+	}
+
+	for _, err := range ret {
+		log.Warning.Printf(err.Error())
+	}
+
+	return ret
+}
+
+/*AddTracerEventBatch streams every queued event over the same client-streaming
+ * RPC as AddTracerEvents, just sourced from a slice instead of a map so more
+ * than one event per tracer ID can be sent in a single batch. */
+func (t *grpcTransport) AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error {
+	c, err := t.client()
+	if err != nil {
+		return []error{err}
+	}
+
+	stream, err := c.AddTracerEvents(ctx, grpcJSONCodec())
+	if err != nil {
+		return []error{wrapGRPCErr("AddTracerEventBatch", err)}
+	}
+
+	var ret []error
+	for _, be := range events {
+		be.Event.TracerID = be.TracerID
+		if err := stream.Send(tracerEventToPB(be.Event)); err != nil {
+			ret = append(ret, wrapGRPCErr("AddTracerEventBatch", err))
+		}
+	}
+
+	if _, err := stream.CloseAndRecv(); err != nil {
+		ret = append(ret, wrapGRPCErr("AddTracerEventBatch", err))
+	}
+
+	for _, err := range ret {
+		log.Warning.Printf(err.Error())
+	}
+
+	return ret
+}
+
+func (t *grpcTransport) AddLabel(ctx context.Context, label types.Label) error {
+	c, err := t.client()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.AddLabel(ctx, labelToPB(label), grpcJSONCodec())
+	return wrapGRPCErr("AddLabel", err)
+}
+
+func (t *grpcTransport) GetLabels(ctx context.Context) ([]types.Label, error) {
+	c, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.GetLabels(ctx, &tracerpb.Empty{}, grpcJSONCodec())
+	if err != nil {
+		return nil, wrapGRPCErr("GetLabels", err)
+	}
+
+	ret := make([]types.Label, 0, len(resp.Labels))
+	for _, l := range resp.Labels {
+		ret = append(ret, labelFromPB(l))
+	}
+	return ret, nil
+}
+
+func (t *grpcTransport) GetLabel(ctx context.Context, ID int) (types.Label, error) {
+	c, err := t.client()
+	if err != nil {
+		return types.Label{}, err
+	}
+
+	resp, err := c.GetLabel(ctx, &tracerpb.LabelID{Id: int32(ID)}, grpcJSONCodec())
+	if err != nil {
+		return types.Label{}, wrapGRPCErr("GetLabel", err)
+	}
+	return labelFromPB(resp), nil
+}
+
+/*SubscribeTracers opens the SubscribeTracers server-streaming RPC and decodes
+ * each message into a TracerUpdate. Messages at or before lastSeenID are
+ * skipped server-side. */
+func (t *grpcTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	c, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := c.SubscribeTracers(ctx, &tracerpb.SubscribeTracersRequest{LastSeenId: int32(lastSeenID)}, grpcJSONCodec())
+	if err != nil {
+		return nil, wrapGRPCErr("SubscribeTracers", err)
+	}
+
+	out := make(chan TracerUpdate)
+	go func() {
+		defer close(out)
+		for {
+			update, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF {
+					log.Warning.Printf(wrapGRPCErr("SubscribeTracers", err).Error())
+				}
+				return
+			}
+
+			select {
+			case out <- TracerUpdate{Tracer: tracerFromPB(update.Tracer), ID: int(update.Id)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+/*SubscribeEvents opens the SubscribeEvents server-streaming RPC for a single
+ * tracer and decodes each message into a types.TracerEvent. Messages at or
+ * before lastSeenID are skipped server-side. */
+func (t *grpcTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	c, err := t.client()
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := c.SubscribeEvents(ctx, &tracerpb.SubscribeEventsRequest{TracerId: int32(tracerID), LastSeenId: int32(lastSeenID)}, grpcJSONCodec())
+	if err != nil {
+		return nil, wrapGRPCErr("SubscribeEvents", err)
+	}
+
+	out := make(chan types.TracerEvent)
+	go func() {
+		defer close(out)
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if err != io.EOF {
+					log.Warning.Printf(wrapGRPCErr("SubscribeEvents", err).Error())
+				}
+				return
+			}
+
+			select {
+			case out <- tracerEventFromPB(event):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+/*wrapGRPCErr folds a gRPC status error into the same NetworkError type the HTTP
+ * transport uses, so callers can handle either transport's errors identically. */
+func wrapGRPCErr(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &NetworkError{Op: op, Err: fmt.Errorf("grpc: %w", err)}
+}
+
+func tracerToPB(t types.Tracer) *tracerpb.Tracer {
+	return &tracerpb.Tracer{
+		Id:               int32(t.ID),
+		TracerString:     t.TracerString,
+		UrlEncodedString: t.URLEncodedString,
+	}
+}
+
+func tracerFromPB(t *tracerpb.Tracer) types.Tracer {
+	return types.Tracer{
+		ID:               int(t.Id),
+		TracerString:     t.TracerString,
+		URLEncodedString: t.UrlEncodedString,
+	}
+}
+
+func tracerEventToPB(e types.TracerEvent) *tracerpb.TracerEvent {
+	return &tracerpb.TracerEvent{
+		Id:        int32(e.ID),
+		TracerId:  int32(e.TracerID),
+		EventType: e.EventType,
+		RawEvent:  e.RawEvent,
+	}
+}
+
+func tracerEventFromPB(e *tracerpb.TracerEvent) types.TracerEvent {
+	return types.TracerEvent{
+		ID:        int(e.Id),
+		TracerID:  int(e.TracerId),
+		EventType: e.EventType,
+		RawEvent:  e.RawEvent,
+	}
+}
+
+func labelToPB(l types.Label) *tracerpb.Label {
+	return &tracerpb.Label{
+		Id:       int32(l.ID),
+		TracerId: int32(l.TracerID),
+		Label:    l.Label,
+	}
+}
+
+func labelFromPB(l *tracerpb.Label) types.Label {
+	return types.Label{
+		ID:       int(l.Id),
+		TracerID: int(l.TracerId),
+		Label:    l.Label,
+	}
+}
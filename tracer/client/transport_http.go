@@ -0,0 +1,437 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+
+	"tracy/configure"
+	"tracy/log"
+	"tracy/tracer/types"
+)
+
+/*httpTransport is the original HTTP+JSON Transport implementation: it POSTs and
+ * GETs JSON-encoded payloads at the tracer-server's REST endpoints. */
+type httpTransport struct{}
+
+func newHTTPTransport() Transport {
+	return httpTransport{}
+}
+
+/*tracerServerURL builds the base URL for the tracer-server out of the "tracer-server"
+ * config value, the same way every request function has always done it. */
+func tracerServerURL() (string, error) {
+	tracerServer, err := configure.ReadConfig("tracer-server")
+	if err != nil {
+		return "", err
+	}
+	return tracerServer.(string), nil
+}
+
+func (httpTransport) AddTracers(ctx context.Context, request types.Request) error {
+	log.Trace.Printf("Adding the following tracers: %+v", request.Tracers)
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s/tracers", tracerServer)
+	// Not idempotent, same as AddTracerEvent/AddLabel below: a lost response
+	// after the server already created the tracers would otherwise create
+	// them again on retry.
+	_, err = postJSON(ctx, "AddTracers", url, request, false)
+	return err
+}
+
+func (httpTransport) GetTracers(ctx context.Context) ([]types.Tracer, error) {
+	log.Trace.Printf("Getting all the tracers")
+	ret := make([]types.Tracer, 0)
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return ret, err
+	}
+
+	url := fmt.Sprintf("http://%s/tracers", tracerServer)
+	err = getJSON(ctx, "GetTracers", url, &ret)
+	return ret, err
+}
+
+func (t httpTransport) AddTracerEvents(ctx context.Context, tracerEvents map[int]types.TracerEvent) []error {
+	log.Trace.Printf("Adding the following tracer events: %+v", tracerEvents)
+	ret := make([]error, 0)
+
+	for tracerID, tracerEvent := range tracerEvents {
+		if err := t.AddTracerEvent(ctx, tracerEvent, tracerID); err != nil {
+			ret = append(ret, err)
+		}
+	}
+
+	return ret
+}
+
+func (httpTransport) AddTracerEvent(ctx context.Context, tracerEvent types.TracerEvent, tracerID int) error {
+	log.Trace.Printf("Adding the following tracer event: %+v, tracer ID: %d", tracerEvent, tracerID)
+	tracerEvent.TracerID = tracerID
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s/tracers/%d/events", tracerServer, tracerID)
+	_, err = postJSON(ctx, "AddTracerEvent", url, tracerEvent, false)
+	return err
+}
+
+/*batchEventsRequest is the payload for the batch event endpoint: every queued
+ * event, grouped by the tracer ID it belongs to. */
+type batchEventsRequest struct {
+	Events map[int][]types.TracerEvent `json:"events"`
+}
+
+/*AddTracerEventBatch POSTs every queued event to the tracer-server in a single
+ * request, grouped by tracer ID. Older tracer-servers that predate the
+ * /tracers/events:batch endpoint answer with a 404, in which case this falls
+ * back to emulating the batch with one AddTracerEvent call per event, the way
+ * EventSink's flushes behaved before this endpoint existed. */
+func (t httpTransport) AddTracerEventBatch(ctx context.Context, events []BatchedEvent) []error {
+	if len(events) == 0 {
+		return nil
+	}
+	log.Trace.Printf("Adding a batch of %d tracer events", len(events))
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return []error{err}
+	}
+
+	grouped := make(map[int][]types.TracerEvent)
+	for _, be := range events {
+		be.Event.TracerID = be.TracerID
+		grouped[be.TracerID] = append(grouped[be.TracerID], be.Event)
+	}
+
+	url := fmt.Sprintf("http://%s/tracers/events:batch", tracerServer)
+	// Not idempotent, same as AddTracerEvent above: a lost response after the
+	// server already persisted the batch would otherwise duplicate every event
+	// in it on retry. EventSink.sendBatch applies its own backoff on top of this.
+	_, err = postJSON(ctx, "AddTracerEventBatch", url, batchEventsRequest{Events: grouped}, false)
+	if err == nil {
+		return nil
+	}
+
+	if serverErr, ok := err.(*ServerError); ok && serverErr.StatusCode == http.StatusNotFound {
+		log.Trace.Printf("tracer-server doesn't support %s, falling back to one request per event", url)
+		var ret []error
+		// Falls back through activeTransport(), not t directly, so the
+		// per-event calls still pass through whatever circuit breaker/rate
+		// limiter/tracing middleware wraps the configured Transport, instead
+		// of a legacy-server fallback silently bypassing them.
+		for _, be := range events {
+			if err := activeTransport().AddTracerEvent(ctx, be.Event, be.TracerID); err != nil {
+				ret = append(ret, err)
+			}
+		}
+		return ret
+	}
+
+	return []error{err}
+}
+
+func (httpTransport) AddLabel(ctx context.Context, label types.Label) error {
+	log.Trace.Printf("Adding the following label: %+v", label)
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("http://%s/labels", tracerServer)
+	_, err = postJSON(ctx, "AddLabel", url, label, false)
+	return err
+}
+
+func (httpTransport) GetLabels(ctx context.Context) ([]types.Label, error) {
+	log.Trace.Printf("Getting all the labels")
+	ret := make([]types.Label, 0)
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return ret, err
+	}
+
+	url := fmt.Sprintf("http://%s/labels", tracerServer)
+	err = getJSON(ctx, "GetLabels", url, &ret)
+	return ret, err
+}
+
+func (httpTransport) GetLabel(ctx context.Context, ID int) (types.Label, error) {
+	log.Trace.Printf("Getting the label %d", ID)
+	ret := types.Label{}
+
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return ret, err
+	}
+
+	url := fmt.Sprintf("http://%s/tracers/%d", tracerServer, ID)
+	err = getJSON(ctx, "GetLabel", url, &ret)
+	return ret, err
+}
+
+/*SubscribeTracers opens a Server-Sent Events connection to the tracer-server and
+ * decodes each event's data as a TracerUpdate. Events at or before lastSeenID
+ * are skipped server-side via the "since" query parameter. */
+func (httpTransport) SubscribeTracers(ctx context.Context, lastSeenID int) (<-chan TracerUpdate, error) {
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("http://%s/tracers/subscribe?since=%d", tracerServer, lastSeenID)
+	raw, err := openSSE(ctx, "SubscribeTracers", url)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan TracerUpdate)
+	go func() {
+		defer close(out)
+		for payload := range raw {
+			var update TracerUpdate
+			if err := json.Unmarshal(payload, &update); err != nil {
+				log.Warning.Printf((&DecodeError{Op: "SubscribeTracers", Err: err}).Error())
+				continue
+			}
+			select {
+			case out <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+/*SubscribeEvents opens a Server-Sent Events connection to the tracer-server for
+ * a single tracer's events and decodes each event's data as a types.TracerEvent.
+ * Events at or before lastSeenID are skipped server-side. */
+func (httpTransport) SubscribeEvents(ctx context.Context, tracerID, lastSeenID int) (<-chan types.TracerEvent, error) {
+	tracerServer, err := tracerServerURL()
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("http://%s/tracers/%d/events/subscribe?since=%d", tracerServer, tracerID, lastSeenID)
+	raw, err := openSSE(ctx, "SubscribeEvents", url)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan types.TracerEvent)
+	go func() {
+		defer close(out)
+		for payload := range raw {
+			var event types.TracerEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				log.Warning.Printf((&DecodeError{Op: "SubscribeEvents", Err: err}).Error())
+				continue
+			}
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+/*sseDataPrefix is the line prefix the tracer-server's SSE endpoints use for the
+ * payload of each event, per the Server-Sent Events spec. */
+const sseDataPrefix = "data: "
+
+/*maxSSELineBytes raises bufio.Scanner's default 64KB line limit, since a
+ * tracer event's RawEvent can carry a full proxied request or response and
+ * would otherwise make openSSE fail permanently (bufio.ErrTooLong) on any
+ * event past that size. */
+const maxSSELineBytes = 1 << 20
+
+/*openSSE opens a GET request for url expecting a text/event-stream response, and
+ * returns a channel of each event's raw "data:" payload. The channel is closed
+ * when ctx is cancelled, the server closes the connection, or the stream errors
+ * out; callers wanting to reconnect should do so at a higher level (see
+ * subscribe.go), since a single attempt here deliberately doesn't retry. */
+func openSSE(ctx context.Context, op, url string) (<-chan []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, &NetworkError{Op: op, Err: err}
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	injectHTTPHeaders(ctx, opentracing.HTTPHeadersCarrier(req.Header))
+
+	log.Trace.Printf("Opening %s subscription to %s", op, url)
+	resp, err := streamingHTTPClient().Do(req)
+	if err != nil {
+		return nil, &NetworkError{Op: op, Err: err}
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &ServerError{Op: op, StatusCode: resp.StatusCode, Body: body}
+	}
+
+	out := make(chan []byte)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), maxSSELineBytes)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, sseDataPrefix) {
+				continue
+			}
+
+			payload := []byte(strings.TrimPrefix(line, sseDataPrefix))
+			select {
+			case out <- payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			log.Warning.Printf("%s: SSE stream ended: %s", op, err.Error())
+		}
+	}()
+
+	return out, nil
+}
+
+/*getJSON issues a GET request for url with ctx and retries, and unmarshals a
+ * successful response body into out. op identifies the call for error messages. */
+func getJSON(ctx context.Context, op, url string, out interface{}) error {
+	return withRetry(ctx, op, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return &NetworkError{Op: op, Err: err}
+		}
+		injectHTTPHeaders(ctx, opentracing.HTTPHeadersCarrier(req.Header))
+
+		log.Trace.Printf("Sending GET request to %s", url)
+		resp, err := sharedHTTPClient().Do(req)
+		if err != nil {
+			return &NetworkError{Op: op, Err: err}
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return &NetworkError{Op: op, Err: err}
+		}
+		log.Trace.Printf("Read the following from the request response: %s", body)
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return &ServerError{Op: op, StatusCode: resp.StatusCode, Body: body}
+		}
+
+		if out != nil {
+			if err := json.Unmarshal(body, out); err != nil {
+				return &DecodeError{Op: op, Err: err}
+			}
+		}
+
+		return nil
+	})
+}
+
+/*postJSON marshals payload, POSTs it to url with ctx and retries, and returns the
+ * raw response body. op identifies the call for error messages. POSTs are only
+ * retried when the caller marks them idempotent, since a 5xx on a non-idempotent
+ * POST may or may not have taken effect server-side. */
+func postJSON(ctx context.Context, op, url string, payload interface{}, idempotent bool) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	log.Trace.Printf("Decoded the request into the following JSON: %s", payloadJSON)
+
+	var respBody []byte
+	do := func() error {
+		contentType := "application/json; charset=UTF-8"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payloadJSON))
+		if err != nil {
+			return &NetworkError{Op: op, Err: err}
+		}
+		req.Header.Set("Content-Type", contentType)
+		injectHTTPHeaders(ctx, opentracing.HTTPHeadersCarrier(req.Header))
+
+		log.Trace.Printf("Sending POST request with %s to %s %s", payloadJSON, url, contentType)
+		resp, err := sharedHTTPClient().Do(req)
+		if err != nil {
+			return &NetworkError{Op: op, Err: err}
+		}
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return &NetworkError{Op: op, Err: err}
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return &ServerError{Op: op, StatusCode: resp.StatusCode, Body: body}
+		}
+
+		respBody = body
+		return nil
+	}
+
+	var retryErr error
+	if idempotent {
+		retryErr = withRetry(ctx, op, do)
+	} else {
+		retryErr = do()
+	}
+
+	return respBody, retryErr
+}
+
+/*withRetry runs fn, retrying with exponential backoff and jitter according to the
+ * configured retry policy as long as fn's error is retryable and ctx hasn't been
+ * cancelled. */
+func withRetry(ctx context.Context, op string, fn func() error) error {
+	policy := configuredRetryPolicy()
+
+	var err error
+	for attempt := 0; attempt < policy.maxAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if !isRetryable(err) {
+			return err
+		}
+		if attempt < policy.maxAttempts-1 {
+			log.Warning.Printf("%s failed (attempt %d/%d), retrying: %s", op, attempt+1, policy.maxAttempts, err.Error())
+			select {
+			case <-time.After(policy.backoff(attempt)):
+			case <-ctx.Done():
+				return &NetworkError{Op: op, Err: ctx.Err()}
+			}
+		}
+	}
+
+	return fmt.Errorf("%s: giving up after %d attempts: %w", op, policy.maxAttempts, err)
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostJSONNonIdempotentDoesNotRetryOn5xx(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := postJSON(context.Background(), "AddTracers", server.URL, map[string]string{"x": "y"}, false)
+	if err == nil {
+		t.Fatalf("expected an error from a 500 response")
+	}
+	if calls != 1 {
+		t.Fatalf("expected exactly 1 call for a non-idempotent POST, got %d", calls)
+	}
+}
+
+func TestPostJSONIdempotentRetriesOn5xx(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := postJSON(context.Background(), "GetTracers", server.URL, map[string]string{"x": "y"}, true)
+	if err == nil {
+		t.Fatalf("expected an error once every retry is exhausted")
+	}
+	if calls != configuredRetryPolicy().maxAttempts {
+		t.Fatalf("expected %d attempts for an idempotent POST, got %d", configuredRetryPolicy().maxAttempts, calls)
+	}
+}
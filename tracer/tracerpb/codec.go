@@ -0,0 +1,40 @@
+package tracerpb
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+/*JSONContentSubtype is the gRPC content-subtype grpcTransport asks for via
+ * grpc.CallContentSubtype on every call (see tracer/client/transport_grpc.go).
+ * The message types in this package are hand-written structs (see
+ * tracer.pb.go) that carry protobuf struct tags but don't implement
+ * proto.Message, so grpc-go's built-in "proto" codec can't marshal them.
+ * Registering jsonCodec under its own subtype name, rather than squatting on
+ * "proto", keeps this package's JSON-over-the-wire workaround scoped to
+ * TracerServiceClient's own calls instead of silently breaking every other
+ * real-protobuf gRPC client or server linked into the same binary. This is an
+ * interim measure until tracer.proto is regenerated through the real
+ * protoc-gen-go / protoc-gen-go-grpc toolchain; a server built against that
+ * toolchain would need to register the same codec under the same subtype to
+ * understand it. */
+const JSONContentSubtype = "tracerpb-json"
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return JSONContentSubtype
+}
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
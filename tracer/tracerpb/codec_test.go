@@ -0,0 +1,28 @@
+package tracerpb
+
+import "testing"
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	codec := jsonCodec{}
+
+	in := &Tracer{Id: 7, TracerString: "abc", UrlEncodedString: "a%20bc"}
+	data, err := codec.Marshal(in)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+
+	var out Tracer
+	if err := codec.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+
+	if out != *in {
+		t.Fatalf("round trip mismatch: got %+v, want %+v", out, *in)
+	}
+}
+
+func TestJSONCodecName(t *testing.T) {
+	if name := (jsonCodec{}).Name(); name != JSONContentSubtype {
+		t.Fatalf("Name() = %q, want %q (this package's own content-subtype, not grpc's default)", name, JSONContentSubtype)
+	}
+}